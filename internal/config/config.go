@@ -0,0 +1,99 @@
+// Package config loads key/value configuration from files of several
+// formats (.properties, .yaml/.yml, .json, .toml, .env) and flattens any
+// nested structure into the dotted-key strings used by sconfe's
+// {{key}} template syntax.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Decoder turns the raw bytes of a config file into a (possibly nested)
+// map. Implementations live one per supported file format.
+type Decoder interface {
+	Decode(data []byte) (map[string]interface{}, error)
+}
+
+// decoders maps a file extension (as returned by filepath.Ext) to the
+// Decoder responsible for it. Registering a new format takes an entry
+// here for ReadFile, plus its extension in the extensions list (files.go)
+// for Files and HasDefault to look for config files of that format.
+var decoders = map[string]Decoder{
+	".properties": propertiesDecoder{},
+	".env":        propertiesDecoder{},
+	".yaml":       yamlDecoder{},
+	".yml":        yamlDecoder{},
+	".json":       jsonDecoder{},
+	".toml":       tomlDecoder{},
+}
+
+// Supported reports whether ext (including the leading dot) has a
+// registered Decoder.
+func Supported(ext string) bool {
+	_, ok := decoders[ext]
+	return ok
+}
+
+// ReadFile decodes filename with the Decoder registered for its extension
+// and flattens the result into a map of dotted keys to string values.
+func ReadFile(filename string) (map[string]string, error) {
+	ext := filepath.Ext(filename)
+	dec, ok := decoders[ext]
+	if !ok {
+		return nil, fmt.Errorf("config: no decoder registered for extension %q", ext)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := dec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("config: decoding %s: %w", filename, err)
+	}
+
+	return Flatten(raw), nil
+}
+
+// Flatten walks a (possibly nested) map and produces dotted keys, e.g.
+// {"server": {"port": 8080}} becomes {"server.port": "8080"}.
+func Flatten(m map[string]interface{}) map[string]string {
+	res := make(map[string]string)
+	flattenInto(res, "", m)
+	return res
+}
+
+func flattenInto(res map[string]string, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			flattenInto(res, key, vv)
+		case map[interface{}]interface{}:
+			flattenInto(res, key, toStringKeyedMap(vv))
+		case json.Number:
+			// Preserve the original digits (e.g. "10485760") instead of
+			// going through float64, which switches to scientific
+			// notation for large integral values.
+			res[key] = vv.String()
+		default:
+			res[key] = fmt.Sprintf("%v", v)
+		}
+	}
+}
+
+func toStringKeyedMap(m map[interface{}]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[fmt.Sprintf("%v", k)] = v
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package config
+
+import "os"
+
+// extensions lists the supported config file extensions in the order they
+// are looked up. Within a single specificity level (all defaults, or all
+// files for one profile), a later extension wins over an earlier one for
+// the same key, since Files appends in this order and callers merge in
+// order.
+var extensions = []string{".properties", ".yaml", ".yml", ".json", ".toml", ".env"}
+
+func exists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// Files returns every default "config.<ext>" file (when present, in
+// extension order), followed by every "config-<profile>.<ext>" file
+// (when present) for each profile in profiles, in profile order and then
+// extension order within a profile. Callers merge the returned files in
+// order, so profile precedence dominates format: a profile-specific file
+// of any format overrides every default file, and a later profile
+// overrides an earlier one regardless of either one's extension.
+func Files(configDir string, profiles []string) []string {
+	var res []string
+
+	for _, ext := range extensions {
+		base := configDir + "/config" + ext
+		if exists(base) {
+			res = append(res, base)
+		}
+	}
+
+	for _, profile := range profiles {
+		if profile == "" {
+			continue
+		}
+		for _, ext := range extensions {
+			current := configDir + "/config-" + profile + ext
+			if exists(current) {
+				res = append(res, current)
+			}
+		}
+	}
+
+	return res
+}
+
+// HasDefault reports whether configDir contains a "config.<ext>" file for
+// at least one supported extension.
+func HasDefault(configDir string) bool {
+	for _, ext := range extensions {
+		if exists(configDir + "/config" + ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile(%s): %v", path, err)
+	}
+}
+
+func TestFilesProfileOverridesFormat(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "config.yaml"))
+	touch(t, filepath.Join(dir, "config-c.properties"))
+
+	files := Files(dir, []string{"a", "b", "c"})
+
+	if len(files) != 2 {
+		t.Fatalf("files = %v, want 2 entries", files)
+	}
+	if files[len(files)-1] != filepath.Join(dir, "config-c.properties") {
+		t.Fatalf("last file = %s, want the profile-specific file to win regardless of extension", files[len(files)-1])
+	}
+}
+
+func TestFilesLaterProfileOverridesEarlier(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "config.properties"))
+	touch(t, filepath.Join(dir, "config-a.toml"))
+	touch(t, filepath.Join(dir, "config-b.properties"))
+
+	files := Files(dir, []string{"a", "b"})
+
+	want := []string{
+		filepath.Join(dir, "config.properties"),
+		filepath.Join(dir, "config-a.toml"),
+		filepath.Join(dir, "config-b.properties"),
+	}
+	if len(files) != len(want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("files[%d] = %s, want %s", i, files[i], want[i])
+		}
+	}
+}
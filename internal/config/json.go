@@ -0,0 +1,24 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// jsonDecoder decodes .json config files.
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+
+	// UseNumber keeps integral values (e.g. "maxBytes": 10485760) as
+	// json.Number instead of float64, so Flatten can print them back as
+	// the original digits rather than switching to scientific notation.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
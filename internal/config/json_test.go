@@ -0,0 +1,15 @@
+package config
+
+import "testing"
+
+func TestJSONDecoderPreservesLargeIntegers(t *testing.T) {
+	raw, err := jsonDecoder{}.Decode([]byte(`{"maxBytes": 10485760}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	got := Flatten(raw)["maxBytes"]
+	if got != "10485760" {
+		t.Fatalf("maxBytes = %q, want %q", got, "10485760")
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// propertiesDecoder parses simple "key=value" files, one entry per line.
+// It also backs the ".env" extension, which uses the same shape.
+type propertiesDecoder struct{}
+
+func (propertiesDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if equal := strings.Index(line, "="); equal >= 0 {
+			if key := strings.TrimSpace(line[:equal]); len(key) > 0 {
+				value := ""
+				if len(line) > equal {
+					value = strings.TrimSpace(line[equal+1:])
+				}
+				res[key] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,14 @@
+package config
+
+import "github.com/BurntSushi/toml"
+
+// tomlDecoder decodes .toml config files.
+type tomlDecoder struct{}
+
+func (tomlDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,14 @@
+package config
+
+import "gopkg.in/yaml.v3"
+
+// yamlDecoder decodes .yaml and .yml config files.
+type yamlDecoder struct{}
+
+func (yamlDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
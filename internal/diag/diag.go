@@ -0,0 +1,57 @@
+// Package diag collects structured diagnostics raised while processing
+// input files, so they can be reported together at the end of a run
+// instead of as one-off log lines scattered through the process.
+package diag
+
+import "sync"
+
+// Severity classifies how serious a Diagnostic is.
+type Severity string
+
+const (
+	// SeverityError diagnostics cause the process to exit non-zero.
+	SeverityError Severity = "error"
+	// SeverityWarning diagnostics are reported but don't affect the exit code.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic records one problem found while expanding a template, with
+// enough context to locate it in the original input file.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Col      int      `json:"col"`
+	Key      string   `json:"key,omitempty"`
+	Kind     string   `json:"kind"`
+	Severity Severity `json:"severity"`
+}
+
+// Reporter collects Diagnostics from concurrent workers.
+type Reporter struct {
+	mu    sync.Mutex
+	diags []Diagnostic
+}
+
+// NewReporter returns an empty Reporter, ready for concurrent use.
+func NewReporter() *Reporter {
+	return &Reporter{}
+}
+
+// Report records d.
+func (r *Reporter) Report(d Diagnostic) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.diags = append(r.diags, d)
+}
+
+// HasSeverity reports whether any recorded Diagnostic has severity sev.
+func (r *Reporter) HasSeverity(sev Severity) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.diags {
+		if d.Severity == sev {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Diagnostics returns a copy of every recorded Diagnostic, sorted by file,
+// then line, then column, so output is deterministic regardless of which
+// worker reported what.
+func (r *Reporter) Diagnostics() []Diagnostic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Diagnostic, len(r.diags))
+	copy(out, r.diags)
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Col < out[j].Col
+	})
+
+	return out
+}
+
+// WriteSummary writes a human-readable summary of every Diagnostic,
+// grouped by file.
+func (r *Reporter) WriteSummary(w io.Writer) error {
+	var currentFile string
+
+	for _, d := range r.Diagnostics() {
+		if d.File != currentFile {
+			if _, err := fmt.Fprintf(w, "%s:\n", d.File); err != nil {
+				return err
+			}
+			currentFile = d.File
+		}
+
+		if _, err := fmt.Fprintf(w, "  %d:%d [%s] %s", d.Line, d.Col, d.Severity, d.Kind); err != nil {
+			return err
+		}
+		if d.Key != "" {
+			if _, err := fmt.Fprintf(w, " (key=%s)", d.Key); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteRecfile writes every Diagnostic as a recfile-style record: one
+// "key: value" block per Diagnostic, separated by a blank line.
+func (r *Reporter) WriteRecfile(w io.Writer) error {
+	for _, d := range r.Diagnostics() {
+		_, err := fmt.Fprintf(w, "file: %s\nline: %d\ncol: %d\nkey: %s\nkind: %s\nseverity: %s\n\n",
+			d.File, d.Line, d.Col, d.Key, d.Kind, d.Severity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes every Diagnostic as a JSON array.
+func (r *Reporter) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r.Diagnostics())
+}
+
+// WriteReport writes every Diagnostic to w using format, one of "text"
+// (WriteSummary), "json" (WriteJSON) or "recfile" (WriteRecfile).
+func (r *Reporter) WriteReport(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		return r.WriteSummary(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "recfile":
+		return r.WriteRecfile(w)
+	default:
+		return fmt.Errorf("diag: unknown report format %q", format)
+	}
+}
@@ -0,0 +1,150 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func reporterWith(diags ...Diagnostic) *Reporter {
+	r := NewReporter()
+	for _, d := range diags {
+		r.Report(d)
+	}
+	return r
+}
+
+func TestDiagnosticsSortsByFileThenLineThenCol(t *testing.T) {
+	r := reporterWith(
+		Diagnostic{File: "b.txt", Line: 1, Col: 1, Kind: "missing_key"},
+		Diagnostic{File: "a.txt", Line: 2, Col: 5, Kind: "missing_key"},
+		Diagnostic{File: "a.txt", Line: 2, Col: 1, Kind: "missing_key"},
+		Diagnostic{File: "a.txt", Line: 1, Col: 9, Kind: "missing_key"},
+	)
+
+	got := r.Diagnostics()
+	want := []struct {
+		file string
+		line int
+		col  int
+	}{
+		{"a.txt", 1, 9},
+		{"a.txt", 2, 1},
+		{"a.txt", 2, 5},
+		{"b.txt", 1, 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Diagnostics() returned %d entries, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].File != w.file || got[i].Line != w.line || got[i].Col != w.col {
+			t.Fatalf("Diagnostics()[%d] = %+v, want file=%s line=%d col=%d", i, got[i], w.file, w.line, w.col)
+		}
+	}
+}
+
+func TestHasSeverity(t *testing.T) {
+	r := reporterWith(Diagnostic{File: "a.txt", Kind: "missing_key", Severity: SeverityWarning})
+
+	if r.HasSeverity(SeverityError) {
+		t.Fatal("HasSeverity(SeverityError) = true, want false")
+	}
+	if !r.HasSeverity(SeverityWarning) {
+		t.Fatal("HasSeverity(SeverityWarning) = false, want true")
+	}
+}
+
+func TestWriteSummaryGroupsByFile(t *testing.T) {
+	r := reporterWith(
+		Diagnostic{File: "a.txt", Line: 1, Col: 3, Key: "server.port", Kind: "missing_key", Severity: SeverityWarning},
+		Diagnostic{File: "a.txt", Line: 2, Col: 7, Kind: "malformed_template", Severity: SeverityError},
+		Diagnostic{File: "b.txt", Line: 1, Col: 1, Key: "server.host", Kind: "missing_key", Severity: SeverityWarning},
+	)
+
+	var buf bytes.Buffer
+	if err := r.WriteSummary(&buf); err != nil {
+		t.Fatalf("WriteSummary: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Count(out, "a.txt:\n") != 1 {
+		t.Fatalf("output = %q, want a.txt header to appear exactly once", out)
+	}
+	if !strings.Contains(out, "1:3 [warning] missing_key (key=server.port)") {
+		t.Fatalf("output = %q, missing expected first diagnostic line", out)
+	}
+	if !strings.Contains(out, "2:7 [error] malformed_template") {
+		t.Fatalf("output = %q, missing expected second diagnostic line", out)
+	}
+	if !strings.Contains(out, "b.txt:\n") {
+		t.Fatalf("output = %q, missing b.txt header", out)
+	}
+}
+
+func TestWriteJSONRoundTrips(t *testing.T) {
+	r := reporterWith(
+		Diagnostic{File: "a.txt", Line: 1, Col: 3, Key: "server.port", Kind: "missing_key", Severity: SeverityWarning},
+	)
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var got []Diagnostic
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(got) != 1 || got[0] != r.Diagnostics()[0] {
+		t.Fatalf("got %+v, want %+v", got, r.Diagnostics())
+	}
+}
+
+func TestWriteRecfileWritesOneBlockPerDiagnostic(t *testing.T) {
+	r := reporterWith(
+		Diagnostic{File: "a.txt", Line: 1, Col: 3, Key: "server.port", Kind: "missing_key", Severity: SeverityWarning},
+		Diagnostic{File: "b.txt", Line: 2, Col: 5, Kind: "malformed_template", Severity: SeverityError},
+	)
+
+	var buf bytes.Buffer
+	if err := r.WriteRecfile(&buf); err != nil {
+		t.Fatalf("WriteRecfile: %v", err)
+	}
+
+	blocks := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n\n")
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2:\n%s", len(blocks), buf.String())
+	}
+	if !strings.Contains(blocks[0], "file: a.txt") || !strings.Contains(blocks[0], "key: server.port") {
+		t.Fatalf("blocks[0] = %q, missing expected fields", blocks[0])
+	}
+	if !strings.Contains(blocks[1], "file: b.txt") || !strings.Contains(blocks[1], "severity: error") {
+		t.Fatalf("blocks[1] = %q, missing expected fields", blocks[1])
+	}
+}
+
+func TestWriteReportDispatchesByFormat(t *testing.T) {
+	r := reporterWith(Diagnostic{File: "a.txt", Line: 1, Col: 1, Kind: "missing_key", Severity: SeverityWarning})
+
+	cases := map[string]string{
+		"":        "a.txt:\n",
+		"text":    "a.txt:\n",
+		"json":    `"file":"a.txt"`,
+		"recfile": "file: a.txt",
+	}
+	for format, want := range cases {
+		var buf bytes.Buffer
+		if err := r.WriteReport(&buf, format); err != nil {
+			t.Fatalf("WriteReport(%q): %v", format, err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("WriteReport(%q) = %q, want it to contain %q", format, buf.String(), want)
+		}
+	}
+
+	if err := r.WriteReport(&bytes.Buffer{}, "xml"); err == nil {
+		t.Fatal("WriteReport(\"xml\") = nil error, want an error for an unknown format")
+	}
+}
@@ -0,0 +1,151 @@
+// Package template resolves the {{key}} placeholder syntax used in
+// sconfe's input templates. It supports default values ({{key:default}}),
+// environment fallback ({{env.VAR}}, {{env.VAR:fallback}}), nested
+// references ({{prefix.{{profile}}.host}}) and \{{ escaping, none of
+// which a single-pass string replace can express.
+package template
+
+import (
+	"os"
+	"strings"
+)
+
+// Lookup resolves a plain (non-env) key against the loaded config, the
+// way the config map built by buildConfigMap does.
+type Lookup func(key string) (string, bool)
+
+// Kind classifies an Issue found while expanding a template string.
+type Kind string
+
+const (
+	// KindMissingKey means a placeholder's key resolved to nothing.
+	KindMissingKey Kind = "missing-key"
+	// KindMalformedTemplate means a "{{" had no matching "}}".
+	KindMalformedTemplate Kind = "malformed-template"
+)
+
+// Issue describes one placeholder that could not be fully resolved. Col is
+// the 0-based byte offset of the offending "{{" within the string passed
+// to Expand.
+type Issue struct {
+	Kind Kind
+	Key  string
+	Col  int
+}
+
+// Expand resolves every placeholder in s, innermost first, and returns the
+// substituted string along with any Issues encountered. When allowMissing
+// is true, an unresolved placeholder's original "{{...}}" text is kept in
+// the output, otherwise it is replaced with the empty string.
+func Expand(s string, lookup Lookup, allowMissing bool) (result string, issues []Issue) {
+	var b strings.Builder
+
+	i := 0
+	for i < len(s) {
+		if hasPrefixAt(s, i, `\{{`) {
+			b.WriteString("{{")
+			i += 3
+			continue
+		}
+
+		if hasPrefixAt(s, i, "{{") {
+			start := i
+			end := matchingEnd(s, i)
+			if end < 0 {
+				b.WriteString(s[i:])
+				issues = append(issues, Issue{Kind: KindMalformedTemplate, Col: start})
+				i = len(s)
+				break
+			}
+
+			inner, innerIssues := Expand(s[i+2:end], lookup, allowMissing)
+			for _, issue := range innerIssues {
+				// issue.Col is relative to the inner substring (s[i+2:end]);
+				// translate it back to an absolute offset into s.
+				issue.Col += start + 2
+				issues = append(issues, issue)
+			}
+
+			value, key, ok := resolve(inner, lookup)
+			if !ok {
+				issues = append(issues, Issue{Kind: KindMissingKey, Key: key, Col: start})
+				if allowMissing {
+					b.WriteString("{{" + inner + "}}")
+				}
+			} else {
+				b.WriteString(value)
+			}
+
+			i = end + 2
+			continue
+		}
+
+		b.WriteByte(s[i])
+		i++
+	}
+
+	return b.String(), issues
+}
+
+// matchingEnd returns the index of the "}}" that closes the "{{" found at
+// start, accounting for "{{" pairs nested inside it, or -1 if s has no
+// matching close.
+func matchingEnd(s string, start int) int {
+	depth := 0
+	i := start
+	for i < len(s) {
+		switch {
+		case hasPrefixAt(s, i, `\{{`):
+			i += 3
+		case hasPrefixAt(s, i, "{{"):
+			depth++
+			i += 2
+		case hasPrefixAt(s, i, "}}"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+			i += 2
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+func hasPrefixAt(s string, i int, prefix string) bool {
+	return i+len(prefix) <= len(s) && s[i:i+len(prefix)] == prefix
+}
+
+// resolve splits inner into a key and an optional ":default" suffix, then
+// resolves the key against os.Getenv (for an "env." prefix) or lookup.
+func resolve(inner string, lookup Lookup) (value string, key string, ok bool) {
+	key = inner
+	def := ""
+	hasDefault := false
+
+	if idx := strings.Index(inner, ":"); idx >= 0 {
+		key = inner[:idx]
+		def = inner[idx+1:]
+		hasDefault = true
+	}
+	key = strings.TrimSpace(key)
+
+	if envVar, isEnv := strings.CutPrefix(key, "env."); isEnv {
+		if v, found := os.LookupEnv(envVar); found {
+			return v, key, true
+		}
+		if hasDefault {
+			return def, key, true
+		}
+		return "", key, false
+	}
+
+	if v, found := lookup(key); found {
+		return v, key, true
+	}
+	if hasDefault {
+		return def, key, true
+	}
+	return "", key, false
+}
@@ -0,0 +1,126 @@
+package template
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func lookupFrom(m map[string]string) Lookup {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func TestExpandPlainKey(t *testing.T) {
+	lookup := lookupFrom(map[string]string{"server.port": "8080"})
+
+	got, issues := Expand("port={{server.port}}", lookup, false)
+	if got != "port=8080" || len(issues) != 0 {
+		t.Fatalf("got %q issues=%v", got, issues)
+	}
+}
+
+func TestExpandDefault(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	got, issues := Expand("port={{server.port:9090}}", lookup, false)
+	if got != "port=9090" || len(issues) != 0 {
+		t.Fatalf("got %q issues=%v", got, issues)
+	}
+}
+
+func TestExpandMissingKey(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	got, issues := Expand("host={{server.host}}", lookup, false)
+	if got != "host=" {
+		t.Fatalf("got %q, want empty substitution", got)
+	}
+	if len(issues) != 1 || issues[0].Kind != KindMissingKey || issues[0].Key != "server.host" {
+		t.Fatalf("issues = %+v", issues)
+	}
+}
+
+func TestExpandAllowMissingKeepsPlaceholder(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	got, issues := Expand("host={{server.host}}", lookup, true)
+	if got != "host={{server.host}}" {
+		t.Fatalf("got %q", got)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("issues = %+v", issues)
+	}
+}
+
+func TestExpandEnvFallback(t *testing.T) {
+	os.Setenv("SCONFE_TEST_VAR", "from-env")
+	defer os.Unsetenv("SCONFE_TEST_VAR")
+	lookup := lookupFrom(map[string]string{})
+
+	got, _ := Expand("{{env.SCONFE_TEST_VAR}}", lookup, false)
+	if got != "from-env" {
+		t.Fatalf("got %q", got)
+	}
+
+	got, _ = Expand("{{env.SCONFE_MISSING:fallback}}", lookup, false)
+	if got != "fallback" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestExpandNestedReference(t *testing.T) {
+	lookup := lookupFrom(map[string]string{
+		"profile":          "prod",
+		"prefix.prod.host": "prod.example.com",
+	})
+
+	got, issues := Expand("{{prefix.{{profile}}.host}}", lookup, false)
+	if got != "prod.example.com" || len(issues) != 0 {
+		t.Fatalf("got %q issues=%v", got, issues)
+	}
+}
+
+func TestExpandEscapedBraces(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	got, issues := Expand(`\{{literal}}`, lookup, false)
+	if got != "{{literal}}" || len(issues) != 0 {
+		t.Fatalf("got %q issues=%v", got, issues)
+	}
+}
+
+func TestExpandNestedMissingKeyReportsInnerColumn(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	s := "a={{missing1}} b={{prefix.{{missing2}}.host}}"
+	_, issues := Expand(s, lookup, false)
+
+	var innerCol, outerCol = -1, -1
+	for _, issue := range issues {
+		switch issue.Key {
+		case "missing2":
+			innerCol = issue.Col
+		case "prefix..host":
+			outerCol = issue.Col
+		}
+	}
+
+	if want := strings.Index(s, "{{missing2}}"); innerCol != want {
+		t.Fatalf("missing2 issue.Col = %d, want %d (its own \"{{\")", innerCol, want)
+	}
+	if want := strings.Index(s, "{{prefix."); outerCol != want {
+		t.Fatalf("outer issue.Col = %d, want %d (the outer \"{{\")", outerCol, want)
+	}
+}
+
+func TestExpandMalformedTemplate(t *testing.T) {
+	lookup := lookupFrom(map[string]string{})
+
+	got, issues := Expand("broken {{server.port", lookup, false)
+	if len(issues) != 1 || issues[0].Kind != KindMalformedTemplate {
+		t.Fatalf("issues = %+v, got %q", issues, got)
+	}
+}
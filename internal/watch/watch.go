@@ -0,0 +1,114 @@
+// Package watch recursively watches a set of root directories for
+// filesystem changes, debouncing bursts of events (e.g. an editor save
+// storm, or several files changing together under the same root) into a
+// single notification per debounce key.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher recursively watches a set of root directories, including
+// subdirectories created after it starts.
+type Watcher struct {
+	fsw      *fsnotify.Watcher
+	debounce time.Duration
+	keyFunc  func(path string) string
+}
+
+// New creates a Watcher for roots, adding every existing subdirectory.
+// Events are coalesced if they occur within debounce of each other and
+// keyFunc maps them to the same key; a burst of events for the same key
+// fires onEvent once, with the most recently changed path. keyFunc
+// defaults to grouping by the exact path (no cross-file coalescing) when
+// nil.
+func New(roots []string, debounce time.Duration, keyFunc func(path string) string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if keyFunc == nil {
+		keyFunc = func(path string) string { return path }
+	}
+
+	w := &Watcher{fsw: fsw, debounce: debounce, keyFunc: keyFunc}
+	for _, root := range roots {
+		if err := w.addRecursive(root); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *Watcher) addRecursive(root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.fsw.Add(p)
+		}
+		return nil
+	})
+}
+
+// Run blocks, invoking onEvent (debounced and coalesced per keyFunc) for
+// every changed path and onError for every watcher error, until Close is
+// called. Newly created directories are watched automatically so files
+// added under them are picked up too.
+func (w *Watcher) Run(onEvent func(path string), onError func(err error)) {
+	var mu sync.Mutex
+	pending := make(map[string]*time.Timer)
+	latest := make(map[string]string)
+
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					w.addRecursive(ev.Name)
+				}
+			}
+
+			key := w.keyFunc(ev.Name)
+			mu.Lock()
+			latest[key] = ev.Name
+			if t, exists := pending[key]; exists {
+				t.Reset(w.debounce)
+			} else {
+				pending[key] = time.AfterFunc(w.debounce, func() {
+					mu.Lock()
+					path := latest[key]
+					delete(pending, key)
+					delete(latest, key)
+					mu.Unlock()
+					onEvent(path)
+				})
+			}
+			mu.Unlock()
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			onError(err)
+		}
+	}
+}
+
+// Close stops the Watcher and causes a running Run call to return.
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
@@ -0,0 +1,144 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunWatchesExistingSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	w, err := New([]string{root}, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer w.Close()
+
+	events := make(chan string, 8)
+	go w.Run(func(p string) { events <- p }, func(err error) { t.Logf("watcher error: %v", err) })
+
+	if err := os.WriteFile(filepath.Join(sub, "file.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	select {
+	case p := <-events:
+		if filepath.Dir(p) != sub {
+			t.Fatalf("event path = %s, want a file under %s", p, sub)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event from the existing subdirectory")
+	}
+}
+
+func TestRunWatchesNewlyCreatedSubdirectory(t *testing.T) {
+	root := t.TempDir()
+
+	w, err := New([]string{root}, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer w.Close()
+
+	events := make(chan string, 8)
+	go w.Run(func(p string) { events <- p }, func(err error) {})
+
+	sub := filepath.Join(root, "new-sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	// Give Run a moment to notice the new directory's Create event and
+	// start watching it before we write into it.
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(sub, "file.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case p := <-events:
+			if filepath.Dir(p) == sub {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for an event from the newly created subdirectory")
+		}
+	}
+}
+
+// TestRunDebouncesBurstsByKey reproduces several files changing together
+// under the same root (e.g. a git checkout) and verifies they coalesce
+// into a single onEvent call when keyFunc groups them by root, the way
+// main.go groups every path under configDir.
+func TestRunDebouncesBurstsByKey(t *testing.T) {
+	root := t.TempDir()
+
+	keyFunc := func(p string) string { return root }
+
+	w, err := New([]string{root}, 150*time.Millisecond, keyFunc)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer w.Close()
+
+	var mu sync.Mutex
+	var count int
+	go w.Run(func(p string) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}, func(err error) {})
+
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(root, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	got := count
+	mu.Unlock()
+
+	if got != 1 {
+		t.Fatalf("onEvent fired %d times for a burst of writes under one key, want 1", got)
+	}
+}
+
+func TestCloseUnblocksRun(t *testing.T) {
+	root := t.TempDir()
+
+	w, err := New([]string{root}, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.Run(func(p string) {}, func(err error) {})
+		close(done)
+	}()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after Close")
+	}
+}
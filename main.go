@@ -2,13 +2,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
-	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/nocquidant/sconfe/internal/config"
+	"github.com/nocquidant/sconfe/internal/diag"
+	"github.com/nocquidant/sconfe/internal/template"
+	"github.com/nocquidant/sconfe/internal/watch"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,11 +31,16 @@ func init() {
 var replacer = strings.NewReplacer("\\", "/")
 
 type env struct {
-	dryRun    bool
-	configDir string
-	inputDir  string
-	outputDir string
-	profiles  []string
+	dryRun       bool
+	configDir    string
+	inputDir     string
+	outputDir    string
+	profiles     []string
+	parallel     int
+	strict       bool
+	allowMissing bool
+	report       string
+	watch        bool
 }
 
 func newEnv() env {
@@ -40,6 +53,11 @@ func newEnv() env {
 	flag.StringVar(&e.inputDir, "inputdir", "./input", "Input path for files to process relative to root path")
 	flag.StringVar(&e.outputDir, "outputdir", "./output", "Output path for processed files relative to root path")
 	profiles := flag.String("profiles", "a,b,c", "List of comma separated profiles")
+	flag.IntVar(&e.parallel, "parallel", runtime.NumCPU(), "Number of worker goroutines used to process files concurrently")
+	flag.BoolVar(&e.strict, "strict", false, "Exit non-zero if any template placeholder could not be resolved")
+	flag.BoolVar(&e.allowMissing, "allow-missing", false, "Leave unresolved placeholders as-is instead of substituting an empty string")
+	flag.StringVar(&e.report, "report", "text", "Format of the diagnostics report printed at the end of the run: text, json or recfile")
+	flag.BoolVar(&e.watch, "watch", false, "After the initial pass, keep running and reprocess files as inputdir/configdir change")
 	e.profiles = strings.Split(*profiles, ",")
 
 	flag.Parse()
@@ -56,11 +74,23 @@ func newEnv() env {
 	e.inputDir = replacer.Replace(path.Clean(path.Join(*rootDir, e.inputDir)))
 	e.outputDir = replacer.Replace(path.Clean(path.Join(*rootDir, e.outputDir)))
 
+	e.parallel = normalizeParallel(e.parallel)
+
 	return e
 }
 
+// normalizeParallel floors a requested worker count to 1, since flag.Int
+// and -parallel=0 (or a negative value) would otherwise leave processFiles
+// with no workers to drain the paths channel.
+func normalizeParallel(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
 func (e *env) toString() {
-	log.Debugf("Parameters are: dryRun=%t, configDir=%s, inputDir=%s, outputDir=%s, profiles=%s", e.dryRun, e.configDir, e.inputDir, e.outputDir, e.profiles)
+	log.Debugf("Parameters are: dryRun=%t, configDir=%s, inputDir=%s, outputDir=%s, profiles=%s, parallel=%d, strict=%t, allowMissing=%t, report=%s, watch=%t", e.dryRun, e.configDir, e.inputDir, e.outputDir, e.profiles, e.parallel, e.strict, e.allowMissing, e.report, e.watch)
 
 }
 
@@ -82,79 +112,25 @@ func isFile(filePath string) bool {
 	return false
 }
 
-func readConfigFile(filename string) (map[string]string, error) {
-	config := make(map[string]string)
-
-	if len(filename) == 0 {
-		return config, nil
-	}
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := bufio.NewScanner(file)
-
-	for reader.Scan() {
-		line := reader.Text()
-
-		// check if the line has = sign
-		// and process the line. Ignore the rest.
-		if equal := strings.Index(line, "="); equal >= 0 {
-			if key := strings.TrimSpace(line[:equal]); len(key) > 0 {
-				value := ""
-				if len(line) > equal {
-					value = strings.TrimSpace(line[equal+1:])
-				}
-				// assign the config map
-				config[key] = value
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-	}
-	return config, nil
-}
-
 func getConfigFiles(e env) []string {
-	// default values
-	file := e.configDir + "/config.properties"
-	if !exists(file) {
-		log.WithFields(log.Fields{"file": file}).Fatal("A default file must exist")
-	}
-
-	res := make([]string, 1)
-	res[0] = file
-
-	// profiles values
-	if len(e.profiles) > 0 && e.profiles[0] != "" {
-		for i := range e.profiles {
-			current := e.configDir + "/config-" + e.profiles[i] + ".properties"
-			if exists(current) {
-				res = append(res, current)
-			}
-		}
+	if !config.HasDefault(e.configDir) {
+		log.WithFields(log.Fields{"dir": e.configDir}).Fatal("A default file must exist")
 	}
 
-	return res
+	return config.Files(e.configDir, e.profiles)
 }
 
 func buildConfigMap(files []string) (map[string]string, error) {
 	res := make(map[string]string)
 
 	for i := range files {
-		config, err := readConfigFile(files[i])
+		parsed, err := config.ReadFile(files[i])
 		if err != nil {
 			return nil, err
 		}
 
 		// merge maps
-		for k, v := range config {
+		for k, v := range parsed {
 			res[k] = v
 		}
 	}
@@ -162,7 +138,14 @@ func buildConfigMap(files []string) (map[string]string, error) {
 	return res, nil
 }
 
-func readWriteFile(e env, config map[string]string, inputPath string) error {
+// readWriteFile substitutes template placeholders in inputPath and writes the
+// result to the matching path under e.outputDir. When e.dryRun is set, the
+// result is written to os.Stdout instead; stdoutMu serializes that shared
+// write so concurrent workers don't interleave their output. Every
+// unresolved or malformed placeholder is recorded on reporter, tagged with
+// the input path, the 1-based line number and the 0-based byte column of
+// the offending "{{".
+func readWriteFile(e env, config map[string]string, inputPath string, stdoutMu *sync.Mutex, reporter *diag.Reporter) error {
 	if !isFile(inputPath) {
 		return nil
 	}
@@ -185,56 +168,133 @@ func readWriteFile(e env, config map[string]string, inputPath string) error {
 	defer outputFile.Close()
 
 	reader := bufio.NewScanner(inputFile)
+
+	var stdoutBuf bytes.Buffer
 	writer := bufio.NewWriter(outputFile)
 	if e.dryRun {
-		writer = bufio.NewWriter(os.Stdout)
+		writer = bufio.NewWriter(&stdoutBuf)
+	}
+
+	lookup := func(key string) (string, bool) {
+		value, ok := config[key]
+		return value, ok
 	}
 
+	lineNum := 0
 	for reader.Scan() {
+		lineNum++
 		line := reader.Text()
-		processedLine := line
-		for {
-			if beginIdx := strings.Index(processedLine, "{{"); beginIdx >= 0 {
-				if endIdx := strings.Index(processedLine, "}}"); endIdx >= 0 {
-					key := strings.TrimSpace(processedLine[beginIdx+2 : endIdx])
-					value := config[key]
-					if value == "" {
-						log.WithFields(log.Fields{
-							"inputPath": inputPath,
-							"line":      line,
-							"key":       key,
-						}).Error("Cannot find value for key")
-					}
-					processedLine = processedLine[0:beginIdx] + value + processedLine[endIdx+2:len(processedLine)]
-					//log.Debug(processedLine)
-				} else {
-					// Beginning template only?!
-					log.WithFields(log.Fields{
-						"inputPath": inputPath,
-						"line":      line,
-					}).Warn("Found malformed template in file")
-					break
-				}
-			} else {
-				break // No template
+
+		processedLine, issues := template.Expand(line, lookup, e.allowMissing)
+
+		for _, issue := range issues {
+			severity := diag.SeverityWarning
+			if issue.Kind == template.KindMissingKey && e.strict {
+				severity = diag.SeverityError
 			}
+			reporter.Report(diag.Diagnostic{
+				File:     inputPath,
+				Line:     lineNum,
+				Col:      issue.Col,
+				Key:      issue.Key,
+				Kind:     string(issue.Kind),
+				Severity: severity,
+			})
 		}
+
 		writer.WriteString(processedLine + "\n")
 	}
 
-	return writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if e.dryRun {
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		_, err := os.Stdout.Write(stdoutBuf.Bytes())
+		return err
+	}
+
+	return nil
+}
+
+// pathError pairs an error with the input path that produced it, so errors
+// gathered from concurrent workers can be reported in a deterministic order.
+type pathError struct {
+	path string
+	err  error
+}
+
+// multiError aggregates the errors raised by the worker pool in processFiles.
+type multiError struct {
+	errs []pathError
+}
+
+func (m *multiError) Error() string {
+	parts := make([]string, len(m.errs))
+	for i, pe := range m.errs {
+		parts[i] = pe.path + ": " + pe.err.Error()
+	}
+	return strings.Join(parts, "; ")
 }
 
-func processFiles(e env, config map[string]string) error {
-	return filepath.Walk(e.inputDir, func(path string, info os.FileInfo, err error) error {
-		path = replacer.Replace(path)
+// processFiles walks e.inputDir and fans the discovered paths out to a pool
+// of e.parallel workers, each running readWriteFile. Errors from every
+// worker are collected rather than aborting the walk, so a failure on one
+// file doesn't prevent its siblings from being processed.
+func processFiles(e env, config map[string]string, reporter *diag.Reporter) error {
+	// Buffered so the walker can queue up work ahead of the pool instead of
+	// lock-stepping with whichever worker happens to be free.
+	paths := make(chan string, e.parallel)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []pathError
+		stdoutMu sync.Mutex
+	)
+
+	wg.Add(e.parallel)
+	for i := 0; i < e.parallel; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				if err := readWriteFile(e, config, p, &stdoutMu, reporter); err != nil {
+					mu.Lock()
+					errs = append(errs, pathError{path: p, err: err})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	walkErr := filepath.Walk(e.inputDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		p = replacer.Replace(p)
 
 		log.WithFields(log.Fields{
-			"path": path,
+			"path": p,
 		}).Debug("Visited item")
 
-		return readWriteFile(e, config, path)
+		paths <- p
+		return nil
 	})
+	close(paths)
+	wg.Wait()
+
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].path < errs[j].path })
+		return &multiError{errs: errs}
+	}
+
+	return nil
 }
 
 func main() {
@@ -246,8 +306,111 @@ func main() {
 	if err != nil {
 		log.Fatalf("Unexpected error when building config map. %s", err)
 	}
-	err = processFiles(e, config)
+
+	reporter := diag.NewReporter()
+	err = processFiles(e, config, reporter)
 	if err != nil {
 		log.Fatalf("Unexpected error when processing files. %s", err)
 	}
+
+	if err := reporter.WriteReport(os.Stdout, e.report); err != nil {
+		log.Fatalf("Unexpected error when writing diagnostics report. %s", err)
+	}
+
+	if !e.watch {
+		if reporter.HasSeverity(diag.SeverityError) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	runWatch(e, config)
+}
+
+// runWatch keeps the process alive after the initial pass, reprocessing a
+// single input file when it changes and the whole pipeline (with the
+// config map reloaded) when anything under e.configDir changes. It never
+// returns; it's the local dev-loop entry point enabled by --watch.
+func runWatch(e env, config map[string]string) {
+	var configMu sync.RWMutex
+
+	// Any number of files changing together under e.configDir (e.g. a git
+	// checkout touching several of them at once) should still trigger a
+	// single full reprocess, so group them all under one debounce key
+	// instead of letting each file arm its own timer. Input files keep
+	// their own identity so only the changed file gets reprocessed.
+	keyFunc := func(p string) string {
+		p = replacer.Replace(p)
+		if strings.HasPrefix(p, e.configDir) {
+			return e.configDir
+		}
+		return p
+	}
+
+	w, err := watch.New([]string{e.inputDir, e.configDir}, 200*time.Millisecond, keyFunc)
+	if err != nil {
+		log.Fatalf("Unexpected error starting watcher. %s", err)
+	}
+	defer w.Close()
+
+	log.WithFields(log.Fields{
+		"inputDir":  e.inputDir,
+		"configDir": e.configDir,
+	}).Info("Watching for changes")
+
+	onEvent := func(p string) {
+		p = replacer.Replace(p)
+
+		var reporter *diag.Reporter
+		var err error
+
+		switch {
+		case strings.HasPrefix(p, e.configDir):
+			log.WithFields(log.Fields{"path": p}).Info("Config changed, reloading and reprocessing all files")
+
+			reloaded, buildErr := buildConfigMap(getConfigFiles(e))
+			if buildErr != nil {
+				log.WithFields(log.Fields{"err": buildErr}).Error("Failed to reload config")
+				return
+			}
+			configMu.Lock()
+			config = reloaded
+			configMu.Unlock()
+
+			configMu.RLock()
+			snapshot := config
+			configMu.RUnlock()
+
+			reporter = diag.NewReporter()
+			err = processFiles(e, snapshot, reporter)
+
+		case strings.HasPrefix(p, e.inputDir):
+			log.WithFields(log.Fields{"path": p}).Info("Input file changed, reprocessing")
+
+			configMu.RLock()
+			snapshot := config
+			configMu.RUnlock()
+
+			var stdoutMu sync.Mutex
+			reporter = diag.NewReporter()
+			err = readWriteFile(e, snapshot, p, &stdoutMu, reporter)
+
+		default:
+			return
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{"path": p, "err": err}).Error("Failed to reprocess after change")
+			return
+		}
+		if err := reporter.WriteReport(os.Stdout, e.report); err != nil {
+			log.WithFields(log.Fields{"err": err}).Error("Failed to write diagnostics report")
+		}
+	}
+
+	onError := func(err error) {
+		log.WithFields(log.Fields{"err": err}).Error("Watcher error")
+	}
+
+	w.Run(onEvent, onError)
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nocquidant/sconfe/internal/diag"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll(%s): %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile(%s): %v", path, err)
+	}
+}
+
+func TestNormalizeParallelFloorsToOne(t *testing.T) {
+	cases := map[int]int{-5: 1, 0: 1, 1: 1, 4: 4}
+	for in, want := range cases {
+		if got := normalizeParallel(in); got != want {
+			t.Errorf("normalizeParallel(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+// TestMultiErrorAggregatesSortedErrorsInOrder sorts the errors by path the
+// way processFiles does before building a multiError, and checks that
+// Error() keeps that order and includes every message.
+func TestMultiErrorAggregatesSortedErrorsInOrder(t *testing.T) {
+	errs := []pathError{
+		{path: "/b.txt", err: errors.New("boom-b")},
+		{path: "/a.txt", err: errors.New("boom-a")},
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].path < errs[j].path })
+
+	got := (&multiError{errs: errs}).Error()
+
+	if idxA, idxB := strings.Index(got, "/a.txt"), strings.Index(got, "/b.txt"); idxA < 0 || idxB < 0 || idxA > idxB {
+		t.Fatalf("Error() = %q, want /a.txt to appear before /b.txt", got)
+	}
+	if !strings.Contains(got, "boom-a") || !strings.Contains(got, "boom-b") {
+		t.Fatalf("Error() = %q, want both underlying error messages", got)
+	}
+}
+
+// TestProcessFilesAggregatesErrorsAndProcessesSiblings verifies that a
+// failure writing one file doesn't stop its siblings from being processed,
+// and that the failure is reported back via a multiError.
+func TestProcessFilesAggregatesErrorsAndProcessesSiblings(t *testing.T) {
+	dir := t.TempDir()
+	inputDir := filepath.Join(dir, "input")
+	outputDir := filepath.Join(dir, "output")
+
+	writeFile(t, filepath.Join(inputDir, "good1.txt"), "hello {{name}}\n")
+	writeFile(t, filepath.Join(inputDir, "good2.txt"), "world {{name}}\n")
+	writeFile(t, filepath.Join(inputDir, "bad", "bad.txt"), "broken {{name}}\n")
+
+	// outputDir/bad already exists as a regular file, so MkdirAll fails
+	// when readWriteFile tries to create it as a directory for bad.txt's
+	// output, while good1.txt/good2.txt (which need no subdirectory)
+	// succeed.
+	writeFile(t, filepath.Join(outputDir, "bad"), "not a directory")
+
+	e := env{inputDir: inputDir, outputDir: outputDir, parallel: 4}
+	reporter := diag.NewReporter()
+
+	err := processFiles(e, map[string]string{"name": "sconfe"}, reporter)
+	if err == nil {
+		t.Fatal("expected an aggregated error from the bad/bad.txt failure")
+	}
+	if !strings.Contains(err.Error(), filepath.Join(inputDir, "bad", "bad.txt")) {
+		t.Fatalf("error = %v, want it to mention the failing path", err)
+	}
+
+	for _, name := range []string{"good1.txt", "good2.txt"} {
+		got, readErr := os.ReadFile(filepath.Join(outputDir, name))
+		if readErr != nil {
+			t.Fatalf("sibling %s was not processed: %v", name, readErr)
+		}
+		if !strings.Contains(string(got), "sconfe") {
+			t.Fatalf("sibling %s not substituted: %q", name, got)
+		}
+	}
+}
+
+// TestProcessFilesDryRunKeepsEachFileContiguous runs several files through
+// processFiles concurrently in dry-run mode and checks that every file's
+// content lands in os.Stdout as one contiguous block, which would not hold
+// if stdoutMu failed to serialize the concurrent writers.
+func TestProcessFilesDryRunKeepsEachFileContiguous(t *testing.T) {
+	dir := t.TempDir()
+	inputDir := filepath.Join(dir, "input")
+	outputDir := filepath.Join(dir, "output")
+
+	contents := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("file%d.txt", i)
+		var b strings.Builder
+		for j := 0; j < 50; j++ {
+			fmt.Fprintf(&b, "file%d-line%d\n", i, j)
+		}
+		writeFile(t, filepath.Join(inputDir, name), b.String())
+		contents[name] = b.String()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	origStdout := os.Stdout
+	os.Stdout = w
+
+	captured := make(chan string, 1)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		captured <- buf.String()
+	}()
+
+	e := env{inputDir: inputDir, outputDir: outputDir, parallel: 8, dryRun: true}
+	reporter := diag.NewReporter()
+	err = processFiles(e, map[string]string{}, reporter)
+
+	w.Close()
+	os.Stdout = origStdout
+	output := <-captured
+
+	if err != nil {
+		t.Fatalf("processFiles: %v", err)
+	}
+
+	for name, content := range contents {
+		if !strings.Contains(output, content) {
+			t.Fatalf("stdout is missing a contiguous block for %s (writers interleaved)", name)
+		}
+	}
+}